@@ -10,14 +10,14 @@ import (
 
 func TestLRUCache(t *testing.T) {
 
-	config := cache.NewCacheConfig(0, 20, cache.ChangeCallbacks{
-		OnAdd: func() {
+	config := cache.NewCacheConfig(0, 20, cache.ChangeCallbackFunc{
+		AddFunc: func() {
 			fmt.Println("entry add...")
 		},
-		OnGet: func() {
+		GetFunc: func() {
 			fmt.Println("entry get...")
 		},
-		OnRemove: func() {
+		RemoveFunc: func() {
 			fmt.Println("entry delete...")
 		},
 	})
@@ -51,14 +51,14 @@ func TestLRUCache(t *testing.T) {
 }
 
 func TestLRUWithTTLCache(t *testing.T) {
-	config := cache.NewCacheConfig(4, 20, cache.ChangeCallbacks{
-		OnAdd: func() {
+	config := cache.NewCacheConfig(4, 20, cache.ChangeCallbackFunc{
+		AddFunc: func() {
 			fmt.Println("entry add...")
 		},
-		OnGet: func() {
+		GetFunc: func() {
 			fmt.Println("entry get...")
 		},
-		OnRemove: func() {
+		RemoveFunc: func() {
 			fmt.Println("entry delete...")
 		},
 	})
@@ -87,14 +87,14 @@ func TestLRUWithTTLCache(t *testing.T) {
 }
 
 func TestTTLCache(t *testing.T) {
-	config := cache.NewCacheConfig(time.Duration(10), 20, cache.ChangeCallbacks{
-		OnAdd: func() {
+	config := cache.NewCacheConfig(time.Duration(10), 20, cache.ChangeCallbackFunc{
+		AddFunc: func() {
 			fmt.Println("entry add...")
 		},
-		OnGet: func() {
+		GetFunc: func() {
 			fmt.Println("entry get...")
 		},
-		OnRemove: func() {
+		RemoveFunc: func() {
 			fmt.Println("entry delete...")
 		},
 	})
@@ -143,3 +143,37 @@ func TestTTLCache(t *testing.T) {
 
 }
 
+func TestShardedCache(t *testing.T) {
+	config := cache.NewCacheConfig(0, 8, cache.ChangeCallbackFunc{
+		AddFunc: func() {
+			fmt.Println("entry add...")
+		},
+		GetFunc: func() {
+			fmt.Println("entry get...")
+		},
+		RemoveFunc: func() {
+			fmt.Println("entry delete...")
+		},
+	})
+	config.WithShards(4)
+
+	shardedCache := cache.NewCache(config.LRUCacheMode(), config)
+
+	for i := 1; i <= 20; i++ {
+		shardedCache.Add(i, fmt.Sprintf("this is test %d", i))
+	}
+
+	log.Println("init sharded lruCache finish")
+	log.Printf("shardedCache size: %d", shardedCache.Size())
+
+	// MaxEntries=8分摊到4个分片后，每个分片容量为2，无论key如何路由，总量都不应超过8
+	if size := shardedCache.Size(); size > 8 {
+		t.Errorf("sharded cache size = %d, want <= 8 (MaxEntries=8 across 4 shards)", size)
+	}
+
+	shardedCache.Add(100, "routed value")
+	if v, ok := shardedCache.Get(100); !ok || v != "routed value" {
+		t.Errorf("get key (100) = (%v, %v), want (\"routed value\", true)", v, ok)
+	}
+}
+
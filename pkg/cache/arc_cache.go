@@ -0,0 +1,300 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// arcCache 实现ARC(Adaptive Replacement Cache)淘汰机制的缓存。
+// 维护四个链表：T1(最近访问一次)、T2(最近访问多次)、B1(T1的幽灵记录)、B2(T2的幽灵记录)，
+// 以及一个自适应参数p，在[0, c]之间根据B1/B2的命中情况自动调整T1的目标容量
+type arcCache struct {
+	c int // 总容量，即maxEntries
+	p int // T1的目标容量
+
+	t1, t2, b1, b2   *list.List
+	t1Elems, t2Elems map[Key]*list.Element // value为 *entry
+	b1Elems, b2Elems map[Key]*list.Element // value为 Key，幽灵记录不保存value
+
+	expiry time.Duration
+
+	stat      *cacheStat
+	callbacks ChangeCallbackHandler
+}
+
+func newARC(maxEntries int, expiry time.Duration) *arcCache {
+	return &arcCache{
+		c:       maxEntries,
+		t1:      list.New(),
+		t2:      list.New(),
+		b1:      list.New(),
+		b2:      list.New(),
+		t1Elems: make(map[Key]*list.Element),
+		t2Elems: make(map[Key]*list.Element),
+		b1Elems: make(map[Key]*list.Element),
+		b2Elems: make(map[Key]*list.Element),
+		expiry:  expiry,
+	}
+}
+
+// bind 绑定统计计数器与回调处理器
+func (c *arcCache) bind(stat *cacheStat, callbacks ChangeCallbackHandler) {
+	c.stat = stat
+	c.callbacks = callbacks
+}
+
+// onEvict 记录被移除时通知回调处理器
+func (c *arcCache) onEvict(key Key, reason EvictReason) {
+	if c.callbacks != nil {
+		c.callbacks.OnEvict(key, reason)
+	}
+}
+
+func arcMin(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func arcMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// add 加入缓存，使用默认过期时间
+func (c *arcCache) add(key Key, value interface{}) []evictedEntry {
+	return c.addWithExpire(key, value, c.expiry)
+}
+
+// addWithExpire 加入缓存，并为该条记录单独指定过期时间，实现ARC的REPLACE淘汰与p值自适应调整。
+// ttl为0表示调用方未指定，使用该缓存自身配置的默认过期时间（c.expiry），而不是让记录立即过期。
+// 若触发了容量淘汰，返回被淘汰的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *arcCache) addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry {
+	if ttl == 0 {
+		ttl = c.expiry
+	}
+	now := time.Now().Add(ttl)
+
+	// 情况一：已经在T1/T2中，更新值并晋升（或保留）到T2
+	if ele, ok := c.t1Elems[key]; ok {
+		e := ele.Value.(*entry)
+		e.value = value
+		e.ttl = now
+		c.t1.Remove(ele)
+		delete(c.t1Elems, key)
+		c.t2Elems[key] = c.t2.PushFront(e)
+		return nil
+	}
+	if ele, ok := c.t2Elems[key]; ok {
+		e := ele.Value.(*entry)
+		e.value = value
+		e.ttl = now
+		c.t2.MoveToFront(ele)
+		return nil
+	}
+
+	// 情况二：命中B1幽灵记录，说明最近访问模式偏好"新"，增大p
+	if ele, ok := c.b1Elems[key]; ok {
+		delta := 1
+		if c.b1.Len() > 0 && c.b2.Len() > c.b1.Len() {
+			delta = c.b2.Len() / c.b1.Len()
+		}
+		c.p = arcMin(c.c, c.p+delta)
+		var evicted []evictedEntry
+		if c.c != 0 {
+			evicted = c.replace(false)
+		}
+		c.b1.Remove(ele)
+		delete(c.b1Elems, key)
+		e := &entry{key: key, value: value, ttl: now}
+		c.t2Elems[key] = c.t2.PushFront(e)
+		return evicted
+	}
+
+	// 情况三：命中B2幽灵记录，说明最近访问模式偏好"频繁"，减小p
+	if ele, ok := c.b2Elems[key]; ok {
+		delta := 1
+		if c.b2.Len() > 0 && c.b1.Len() > c.b2.Len() {
+			delta = c.b1.Len() / c.b2.Len()
+		}
+		c.p = arcMax(0, c.p-delta)
+		var evicted []evictedEntry
+		if c.c != 0 {
+			evicted = c.replace(true)
+		}
+		c.b2.Remove(ele)
+		delete(c.b2Elems, key)
+		e := &entry{key: key, value: value, ttl: now}
+		c.t2Elems[key] = c.t2.PushFront(e)
+		return evicted
+	}
+
+	// 情况四：全新的key
+	var evicted []evictedEntry
+	if c.c != 0 {
+		l1Len := c.t1.Len() + c.b1.Len()
+		total := c.t1.Len() + c.t2.Len() + c.b1.Len() + c.b2.Len()
+		if l1Len == c.c {
+			if c.t1.Len() < c.c {
+				c.removeGhostLRU(c.b1, c.b1Elems)
+				evicted = c.replace(false)
+			} else {
+				old := c.t1.Back()
+				k := old.Value.(*entry).key
+				c.t1.Remove(old)
+				delete(c.t1Elems, k)
+				c.stat.addEviction()
+				evicted = []evictedEntry{{key: k, reason: ReasonCapacity}}
+			}
+		} else if l1Len < c.c && total >= c.c {
+			if total == 2*c.c {
+				c.removeGhostLRU(c.b2, c.b2Elems)
+			}
+			evicted = c.replace(false)
+		}
+	}
+	e := &entry{key: key, value: value, ttl: now}
+	c.t1Elems[key] = c.t1.PushFront(e)
+	return evicted
+}
+
+// replace 按照ARC算法从T1或T2淘汰一个entry，移入对应的幽灵链表B1/B2，返回被淘汰的记录，
+// 由调用方在释放锁之后再触发OnEvict回调
+func (c *arcCache) replace(inB2 bool) []evictedEntry {
+	t1Len := c.t1.Len()
+	if t1Len > 0 && (t1Len > c.p || (inB2 && t1Len == c.p)) {
+		old := c.t1.Back()
+		if old == nil {
+			return nil
+		}
+		k := old.Value.(*entry).key
+		c.t1.Remove(old)
+		delete(c.t1Elems, k)
+		c.b1Elems[k] = c.b1.PushFront(k)
+		c.stat.addEviction()
+		return []evictedEntry{{key: k, reason: ReasonCapacity}}
+	}
+	old := c.t2.Back()
+	if old == nil {
+		return nil
+	}
+	k := old.Value.(*entry).key
+	c.t2.Remove(old)
+	delete(c.t2Elems, k)
+	c.b2Elems[k] = c.b2.PushFront(k)
+	c.stat.addEviction()
+	return []evictedEntry{{key: k, reason: ReasonCapacity}}
+}
+
+// removeGhostLRU 淘汰幽灵链表中最老的记录
+func (c *arcCache) removeGhostLRU(ghost *list.List, elems map[Key]*list.Element) {
+	old := ghost.Back()
+	if old == nil {
+		return
+	}
+	ghost.Remove(old)
+	delete(elems, old.Value.(Key))
+}
+
+func (c *arcCache) size() int {
+	return len(c.t1Elems) + len(c.t2Elems)
+}
+
+// get 获取缓存，命中T1时晋升到T2，命中T2时移动到T2最前端
+func (c *arcCache) get(key Key) (value interface{}, ok bool) {
+	if ele, hit := c.t1Elems[key]; hit {
+		e := ele.Value.(*entry)
+		c.t1.Remove(ele)
+		delete(c.t1Elems, key)
+		if time.Now().After(e.ttl) {
+			c.stat.addExpiration()
+			c.onEvict(key, ReasonExpired)
+			c.stat.addMiss()
+			return
+		}
+		c.t2Elems[key] = c.t2.PushFront(e)
+		c.stat.addHit()
+		return e.value, true
+	}
+	if ele, hit := c.t2Elems[key]; hit {
+		e := ele.Value.(*entry)
+		if time.Now().After(e.ttl) {
+			c.t2.Remove(ele)
+			delete(c.t2Elems, key)
+			c.stat.addExpiration()
+			c.onEvict(key, ReasonExpired)
+			c.stat.addMiss()
+			return
+		}
+		c.t2.MoveToFront(ele)
+		c.stat.addHit()
+		return e.value, true
+	}
+	c.stat.addMiss()
+	return
+}
+
+// iterate 遍历T1/T2中存活的记录（幽灵记录B1/B2不保存value，不参与遍历），fn返回false时提前终止
+func (c *arcCache) iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool) {
+	for _, ele := range c.t1Elems {
+		e := ele.Value.(*entry)
+		if !fn(e.key, e.value, e.ttl) {
+			return
+		}
+	}
+	for _, ele := range c.t2Elems {
+		e := ele.Value.(*entry)
+		if !fn(e.key, e.value, e.ttl) {
+			return
+		}
+	}
+}
+
+// remove 删除缓存，T1/T2/B1/B2中无论命中哪个都会被清除。命中T1/T2时返回被删除的记录，
+// 由调用方在释放锁之后再触发OnEvict回调；幽灵记录B1/B2不保存value，不触发回调
+func (c *arcCache) remove(key Key) []evictedEntry {
+	if ele, hit := c.t1Elems[key]; hit {
+		c.t1.Remove(ele)
+		delete(c.t1Elems, key)
+		return []evictedEntry{{key: key, reason: ReasonManual}}
+	}
+	if ele, hit := c.t2Elems[key]; hit {
+		c.t2.Remove(ele)
+		delete(c.t2Elems, key)
+		return []evictedEntry{{key: key, reason: ReasonManual}}
+	}
+	if ele, hit := c.b1Elems[key]; hit {
+		c.b1.Remove(ele)
+		delete(c.b1Elems, key)
+		return nil
+	}
+	if ele, hit := c.b2Elems[key]; hit {
+		c.b2.Remove(ele)
+		delete(c.b2Elems, key)
+	}
+	return nil
+}
+
+// clear 清理所有缓存，返回清空前T1/T2中的全部记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *arcCache) clear() []evictedEntry {
+	evicted := make([]evictedEntry, 0, len(c.t1Elems)+len(c.t2Elems))
+	for key := range c.t1Elems {
+		evicted = append(evicted, evictedEntry{key: key, reason: ReasonClear})
+	}
+	for key := range c.t2Elems {
+		evicted = append(evicted, evictedEntry{key: key, reason: ReasonClear})
+	}
+	c.p = 0
+	c.t1 = list.New()
+	c.t2 = list.New()
+	c.b1 = list.New()
+	c.b2 = list.New()
+	c.t1Elems = make(map[Key]*list.Element)
+	c.t2Elems = make(map[Key]*list.Element)
+	c.b1Elems = make(map[Key]*list.Element)
+	c.b2Elems = make(map[Key]*list.Element)
+	return evicted
+}
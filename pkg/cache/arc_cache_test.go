@@ -0,0 +1,28 @@
+package cache
+
+import "testing"
+
+// TestARCEvictionOrder 验证ARC会优先淘汰只访问过一次、停留在T1中的记录，
+// 而已经被再次访问过、晋升到T2的记录能在容量压力下存活下来
+func TestARCEvictionOrder(t *testing.T) {
+	config := NewCacheConfig(0, 2, nil)
+	c := NewCache(config.ARCCacheMode(), config)
+
+	c.Add(1, "a")
+	c.Get(1) // 命中一次，从T1晋升到T2
+
+	c.Add(2, "b") // 留在T1
+
+	// 容量已满，新key入场会从T1淘汰最老的记录(key 2)，T2中的key 1不受影响
+	c.Add(3, "c")
+
+	if v, ok := c.Get(1); !ok || v != "a" {
+		t.Errorf("Get(1) = (%v, %v), want (\"a\", true): promoted entry should survive capacity eviction", v, ok)
+	}
+	if _, ok := c.Get(2); ok {
+		t.Errorf("key 2 should have been evicted from T1 to make room")
+	}
+	if v, ok := c.Get(3); !ok || v != "c" {
+		t.Errorf("Get(3) = (%v, %v), want (\"c\", true)", v, ok)
+	}
+}
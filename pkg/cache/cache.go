@@ -16,20 +16,71 @@ type Cache struct {
 	lock  sync.Mutex
 	// Config 缓存配置项
 	Config *CacheConfig
+
+	// group 维护GetOrLoad正在进行中的loader调用，用于合并对同一个key的并发请求
+	group   map[Key]*call
+	groupMu sync.Mutex
+
+	// stat 统计计数器，绑定给底层ICache实现，由其在命中/未命中/淘汰等事件发生时自增
+	stat *cacheStat
+
+	// closeCh/resetCh 用于控制后台janitor goroutine：closeCh在Close()时关闭使其退出，
+	// resetCh在新增记录（Add/SetWithExpire）或Clear()之后被通知，促使janitor重新计算下一次唤醒时间
+	closeOnce sync.Once
+	closeCh   chan struct{}
+	resetCh   chan struct{}
 }
 
 func NewCache(cache ICache, config *CacheConfig) *Cache {
-	return &Cache{Cache: cache, Config: config, lock: sync.Mutex{}}
+	stat := &cacheStat{}
+	cache.bind(stat, config.Callbacks)
+	c := &Cache{
+		Cache:   cache,
+		Config:  config,
+		lock:    sync.Mutex{},
+		group:   make(map[Key]*call),
+		stat:    stat,
+		closeCh: make(chan struct{}),
+		resetCh: make(chan struct{}, 1),
+	}
+	if exp, ok := cache.(expirable); ok {
+		go c.runJanitor(exp)
+	}
+	return c
+}
+
+// call 代表一次正在进行中（或刚完成）的loader调用
+type call struct {
+	wg    sync.WaitGroup
+	value interface{}
+	err   error
 }
 
 type CacheConfig struct {
 	// TTL 过期时间，如果有使用，可以设置，不使用可以为空。
 	// 如果需要使用，但没有设置，会默认使用10s过期时间
 	TTL time.Duration
-	// MaxEntries 最大缓存数
+	// MaxEntries 最大缓存数。开启分片模式(Shards>0)时，这是所有分片加起来的总容量上限，
+	// 会按实际分片数量平均（向上取整）分配到每个分片，而不是每个分片各自的上限
 	MaxEntries int
 	// Callbacks 当缓存出现修改时，可执行的回调方法
 	Callbacks ChangeCallbackHandler
+	// Shards 分片数量，大于0时XXXCacheMode()会返回一个ShardedCache，每个分片拥有独立的锁
+	Shards int
+	// SerializeFunc 自定义序列化函数，Snapshot时用于将key/value编码为[]byte，为空时使用encoding/gob
+	SerializeFunc func(interface{}) ([]byte, error)
+	// DeserializeFunc 自定义反序列化函数，与SerializeFunc配对使用，为空时使用encoding/gob
+	DeserializeFunc func([]byte) (interface{}, error)
+}
+
+// WithShards 开启分片模式，之后调用的XXXCacheMode()会返回一个包含n个分片的ShardedCache，
+// 每个分片拥有独立的锁与底层缓存实现，用以缓解高并发下单一锁带来的竞争。n会被取整到最近的2的幂，默认256
+func (cc *CacheConfig) WithShards(n int) *CacheConfig {
+	if n <= 0 {
+		n = defaultShardCount
+	}
+	cc.Shards = n
+	return cc
 }
 
 func NewCacheConfig(TTL time.Duration, maxEntries int, callbacks ChangeCallbackHandler) *CacheConfig {
@@ -41,6 +92,8 @@ type ChangeCallbackHandler interface {
 	OnAdd()
 	OnGet()
 	OnRemove()
+	// OnEvict 当一条记录因容量、过期、手动删除或清空而被移除时调用，reason说明具体原因
+	OnEvict(key Key, reason EvictReason)
 }
 
 // ChangeCallbackFunc 回调方法
@@ -51,6 +104,8 @@ type ChangeCallbackFunc struct {
 	GetFunc func()
 	// OnRemove 删除缓存时，可执行的回调
 	RemoveFunc func()
+	// OnEvict 记录被移除时，可执行的回调
+	EvictFunc func(key Key, reason EvictReason)
 }
 
 func (c ChangeCallbackFunc) OnAdd() {
@@ -71,6 +126,12 @@ func (c ChangeCallbackFunc) OnRemove() {
 	}
 }
 
+func (c ChangeCallbackFunc) OnEvict(key Key, reason EvictReason) {
+	if c.EvictFunc != nil {
+		c.EvictFunc(key, reason)
+	}
+}
+
 type Key interface{}
 
 // entry 存入缓存的Value对象
@@ -85,10 +146,24 @@ const (
 	defaultDuration time.Duration = 10
 )
 
+// shardMaxEntries 将MaxEntries平均（向上取整）分配到每个分片，保证所有分片加起来的
+// 总容量仍然等于MaxEntries，而不是每个分片各自拥有MaxEntries。MaxEntries<=0表示不限制，
+// 原样传给每个分片即可
+func (cc *CacheConfig) shardMaxEntries() int {
+	if cc.MaxEntries <= 0 {
+		return cc.MaxEntries
+	}
+	n := nextPowerOfTwo(cc.Shards)
+	return (cc.MaxEntries + n - 1) / n
+}
+
 // LRUCacheMode LRUCache缓存模式
 func (cc *CacheConfig) LRUCacheMode() ICache {
-	c := newLRU(cc.MaxEntries, maxDuration)
-	return c
+	if cc.Shards > 0 {
+		perShard := cc.shardMaxEntries()
+		return newShardedCache(cc.Shards, func() ICache { return newLRU(perShard, maxDuration) })
+	}
+	return newLRU(cc.MaxEntries, maxDuration)
 }
 
 // LRUWithTTLCacheMode LRUWithTTL缓存模式，如果没有设置，就使用默认过期时间
@@ -96,8 +171,11 @@ func (cc *CacheConfig) LRUWithTTLCacheMode() ICache {
 	if cc.TTL == 0 {
 		cc.TTL = defaultDuration
 	}
-	c := newLRU(cc.MaxEntries, cc.TTL)
-	return c
+	if cc.Shards > 0 {
+		perShard := cc.shardMaxEntries()
+		return newShardedCache(cc.Shards, func() ICache { return newLRU(perShard, cc.TTL) })
+	}
+	return newLRU(cc.MaxEntries, cc.TTL)
 }
 
 // TTLCacheMode TTL缓存模式，如果没有设置，就使用默认过期时间
@@ -105,31 +183,72 @@ func (cc *CacheConfig) TTLCacheMode(updateAgeOnGet bool) ICache {
 	if cc.TTL == 0 {
 		cc.TTL = defaultDuration
 	}
-	c := newTTLCache(cc.MaxEntries, cc.TTL, updateAgeOnGet)
-	return c
+	if cc.Shards > 0 {
+		perShard := cc.shardMaxEntries()
+		return newShardedCache(cc.Shards, func() ICache { return newTTLCache(perShard, cc.TTL, updateAgeOnGet) })
+	}
+	return newTTLCache(cc.MaxEntries, cc.TTL, updateAgeOnGet)
+}
+
+// LFUCacheMode LFUCache缓存模式，按访问频率淘汰最少使用的记录
+func (cc *CacheConfig) LFUCacheMode() ICache {
+	if cc.Shards > 0 {
+		perShard := cc.shardMaxEntries()
+		return newShardedCache(cc.Shards, func() ICache { return newLFU(perShard, maxDuration) })
+	}
+	return newLFU(cc.MaxEntries, maxDuration)
+}
+
+// ARCCacheMode ARCCache缓存模式，在最近访问与高频访问之间自适应调整淘汰策略
+func (cc *CacheConfig) ARCCacheMode() ICache {
+	if cc.Shards > 0 {
+		perShard := cc.shardMaxEntries()
+		return newShardedCache(cc.Shards, func() ICache { return newARC(perShard, maxDuration) })
+	}
+	return newARC(cc.MaxEntries, maxDuration)
+}
+
+// sharded 当底层缓存是ShardedCache时返回true，此时加锁已下沉到各个分片内部，
+// Cache自身的lock不再需要参与，以免重新变回单锁瓶颈
+func (c *Cache) sharded() bool {
+	_, ok := c.Cache.(*ShardedCache)
+	return ok
+}
+
+// notifyJanitor 通知janitor重新计算下一次唤醒时间，用于新增/清空记录之后，
+// 避免janitor一直睡到之前算好的（现在已经过时的）唤醒时间，过期记录只能等下一次Get才被动发现
+func (c *Cache) notifyJanitor() {
+	select {
+	case c.resetCh <- struct{}{}:
+	default:
+	}
 }
 
 // Add 放入缓存，如果OnAdd回调有值，就会调用
 func (c *Cache) Add(key Key, value interface{}) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.Cache.add(key, value)
-	if c.Config.Callbacks.OnAdd != nil {
+	evicted := c.withCacheLockEvict(func() []evictedEntry { return c.Cache.add(key, value) })
+	c.notifyJanitor()
+	if c.Config.Callbacks != nil {
 		c.Config.Callbacks.OnAdd()
 	}
+	c.fireEvicted(evicted)
 }
 
 func (c *Cache) Size() int {
-	c.lock.Lock()
-	defer c.lock.Unlock()
+	if !c.sharded() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
 	return c.Cache.size()
 }
 
 // Get 获取缓存，如果OnGet回调有值，就会调用
 func (c *Cache) Get(key Key) (value interface{}, ok bool) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	if c.Config.Callbacks.OnGet != nil {
+	if !c.sharded() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	if c.Config.Callbacks != nil {
 		c.Config.Callbacks.OnGet()
 	}
 
@@ -138,16 +257,164 @@ func (c *Cache) Get(key Key) (value interface{}, ok bool) {
 
 // Remove 删除缓存，如果OnRemove回调有值，就会调用
 func (c *Cache) Remove(key Key) {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.Cache.remove(key)
-	if c.Config.Callbacks.OnRemove != nil {
+	evicted := c.withCacheLockEvict(func() []evictedEntry { return c.Cache.remove(key) })
+	if c.Config.Callbacks != nil {
 		c.Config.Callbacks.OnRemove()
 	}
+	c.fireEvicted(evicted)
 }
 
 func (c *Cache) Clear() {
-	c.lock.Lock()
-	defer c.lock.Unlock()
-	c.Cache.clear()
+	evicted := c.withCacheLockEvict(func() []evictedEntry { return c.Cache.clear() })
+	// 清空之后待过期的记录也没有了，通知janitor重新计算下一次唤醒时间
+	c.notifyJanitor()
+	c.fireEvicted(evicted)
+}
+
+// SetWithExpire 放入缓存，并为该条记录单独指定过期时间（覆盖CacheConfig.TTL）
+func (c *Cache) SetWithExpire(key Key, value interface{}, ttl time.Duration) {
+	evicted := c.withCacheLockEvict(func() []evictedEntry { return c.Cache.addWithExpire(key, value, ttl) })
+	c.notifyJanitor()
+	if c.Config.Callbacks != nil {
+		c.Config.Callbacks.OnAdd()
+	}
+	c.fireEvicted(evicted)
+}
+
+// GetOrLoad 获取缓存，如果未命中就调用loader加载并写入缓存。
+// 同一时刻对同一个key的并发请求只会有一个真正执行loader（singleflight），其余请求等待共享其结果，
+// 避免缓存失效瞬间大量请求穿透到下游（cache stampede）。loader返回的TTL为0时，
+// 使用CacheConfig.TTL；如果连CacheConfig.TTL也未设置（非TTL模式下恒为0），
+// 则由底层缓存实现自己的addWithExpire在ttl为0时回退到其自身配置的默认过期时间，
+// 而不会把记录写入后立即过期
+func (c *Cache) GetOrLoad(key Key, loader func(Key) (interface{}, time.Duration, error)) (interface{}, error) {
+	if value, ok := c.Get(key); ok {
+		return value, nil
+	}
+
+	c.groupMu.Lock()
+	if ca, ok := c.group[key]; ok {
+		c.groupMu.Unlock()
+		ca.wg.Wait()
+		return ca.value, ca.err
+	}
+	ca := new(call)
+	ca.wg.Add(1)
+	c.group[key] = ca
+	c.groupMu.Unlock()
+
+	value, ttl, err := loader(key)
+	ca.value, ca.err = value, err
+	ca.wg.Done()
+
+	c.groupMu.Lock()
+	delete(c.group, key)
+	c.groupMu.Unlock()
+
+	if err != nil {
+		c.stat.addLoaderErr()
+		return nil, err
+	}
+	if ttl == 0 {
+		ttl = c.Config.TTL
+	}
+	c.SetWithExpire(key, value, ttl)
+	return value, nil
+}
+
+// janitorIdleInterval 当前没有任何记录等待过期时，janitor的巡检间隔
+const janitorIdleInterval = time.Hour
+
+// expirable 由支持主动过期清理的ICache实现（目前为ttlCache及其ShardedCache包装），
+// janitor借此得知下一次应该唤醒的时间，并在唤醒后清理所有已过期的记录
+type expirable interface {
+	// nextExpireAt 返回最早到期的时间，没有记录时ok为false
+	nextExpireAt() (at time.Time, ok bool)
+	// expireOverdue 清理所有已过期的记录并返回被清理的key
+	expireOverdue(now time.Time) []Key
+}
+
+// runJanitor 后台巡检goroutine，在最早的过期时间到来时唤醒并清理过期记录，
+// 而不必等待下一次Get才发现某条记录已经过期
+func (c *Cache) runJanitor(exp expirable) {
+	timer := time.NewTimer(c.nextJanitorWait(exp))
+	defer timer.Stop()
+	for {
+		select {
+		case <-c.closeCh:
+			return
+		case <-c.resetCh:
+			if !timer.Stop() {
+				select {
+				case <-timer.C:
+				default:
+				}
+			}
+			timer.Reset(c.nextJanitorWait(exp))
+		case <-timer.C:
+			c.sweepExpired(exp)
+			timer.Reset(c.nextJanitorWait(exp))
+		}
+	}
+}
+
+// nextJanitorWait 计算janitor下一次应该等待多久再醒来
+func (c *Cache) nextJanitorWait(exp expirable) time.Duration {
+	var at time.Time
+	var ok bool
+	c.withCacheLock(func() { at, ok = exp.nextExpireAt() })
+	if !ok {
+		return janitorIdleInterval
+	}
+	if d := time.Until(at); d > 0 {
+		return d
+	}
+	return 0
+}
+
+// sweepExpired 清理所有已过期的记录，并在释放锁之后再触发OnEvict回调，避免长时间持锁
+func (c *Cache) sweepExpired(exp expirable) {
+	var expired []Key
+	c.withCacheLock(func() { expired = exp.expireOverdue(time.Now()) })
+	if c.Config.Callbacks == nil {
+		return
+	}
+	for _, key := range expired {
+		c.Config.Callbacks.OnEvict(key, ReasonExpired)
+	}
+}
+
+// withCacheLock 在非分片模式下持有Cache自身的锁执行fn，分片模式下加锁已下沉到各分片，无需重复加锁
+func (c *Cache) withCacheLock(fn func()) {
+	if !c.sharded() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	fn()
+}
+
+// withCacheLockEvict 与withCacheLock类似，用于持锁执行会返回被淘汰记录的fn（add/remove/clear等）。
+// 锁在此函数返回时就已经释放，调用方应该在这之后再调用fireEvicted，避免在持锁期间触发OnEvict回调导致死锁
+func (c *Cache) withCacheLockEvict(fn func() []evictedEntry) []evictedEntry {
+	if !c.sharded() {
+		c.lock.Lock()
+		defer c.lock.Unlock()
+	}
+	return fn()
+}
+
+// fireEvicted 在锁外依次触发OnEvict回调，与sweepExpired对容量/手动/清空淘汰做同样处理，
+// 避免回调函数重新进入Cache时死锁
+func (c *Cache) fireEvicted(evicted []evictedEntry) {
+	if c.Config.Callbacks == nil {
+		return
+	}
+	for _, e := range evicted {
+		c.Config.Callbacks.OnEvict(e.key, e.reason)
+	}
+}
+
+// Close 停止后台janitor goroutine。Cache被Close之后不应再被使用
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() { close(c.closeCh) })
 }
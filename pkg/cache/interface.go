@@ -1,15 +1,24 @@
 package cache
 
+import "time"
+
 // ICache 接口对象，需要实现缓存各个方法
 type ICache interface {
-	// add 放入缓存
-	add(key Key, value interface{})
+	// add 放入缓存。若触发了容量淘汰，返回被淘汰的记录，由调用方在释放锁之后再触发OnEvict回调
+	add(key Key, value interface{}) []evictedEntry
+	// addWithExpire 放入缓存，并指定该条记录独立的过期时间。返回值含义同add
+	addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry
+	// bind 绑定统计计数器与回调处理器，由NewCache在构造时调用
+	bind(stat *cacheStat, callbacks ChangeCallbackHandler)
+	// iterate 遍历所有存活的记录，fn返回false时提前终止遍历。用于Snapshot等需要
+	// 不经拷贝整个map就能访问全部记录的场景
+	iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool)
 	// size 数量
 	size() int
 	// get 获取缓存
 	get(key Key) (value interface{}, ok bool)
-	// remove 删除缓存
-	remove(key Key)
-	// clear 清理所有缓存
-	clear()
+	// remove 删除缓存，若命中返回被删除的记录，由调用方在释放锁之后再触发OnEvict回调
+	remove(key Key) []evictedEntry
+	// clear 清理所有缓存，返回清空前的全部记录，由调用方在释放锁之后再触发OnEvict回调
+	clear() []evictedEntry
 }
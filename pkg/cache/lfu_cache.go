@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"container/list"
+	"time"
+)
+
+// lfuFreqNode 维护同一访问频率下的所有entry，freqs链表按频率从小到大排列
+type lfuFreqNode struct {
+	freq  int
+	items *list.List // 元素为 *lfuItem
+}
+
+// lfuItem 存入LFU缓存的记录，freqNode指向其所在的频率节点
+type lfuItem struct {
+	key      Key
+	value    interface{}
+	ttl      time.Time
+	freqNode *list.Element
+}
+
+// lfuCache 实现LFU淘汰机制的缓存，使用频率桶（O(1) LFU）保证get/add的淘汰选择都是O(1)，
+// 每次get命中后，entry会从当前频率桶移动到freq+1的桶中
+type lfuCache struct {
+	maxEntries int
+	expiry     time.Duration
+	items      map[Key]*list.Element // key -> 元素在其所在频率桶items链表中的位置
+	freqs      *list.List            // 元素为 *lfuFreqNode
+
+	stat      *cacheStat
+	callbacks ChangeCallbackHandler
+}
+
+func newLFU(maxEntries int, expiry time.Duration) *lfuCache {
+	return &lfuCache{
+		maxEntries: maxEntries,
+		expiry:     expiry,
+		items:      make(map[Key]*list.Element),
+		freqs:      list.New(),
+	}
+}
+
+// bind 绑定统计计数器与回调处理器
+func (c *lfuCache) bind(stat *cacheStat, callbacks ChangeCallbackHandler) {
+	c.stat = stat
+	c.callbacks = callbacks
+}
+
+// onEvict 记录被移除时通知回调处理器
+func (c *lfuCache) onEvict(key Key, reason EvictReason) {
+	if c.callbacks != nil {
+		c.callbacks.OnEvict(key, reason)
+	}
+}
+
+// add 加入缓存，使用默认过期时间
+func (c *lfuCache) add(key Key, value interface{}) []evictedEntry {
+	return c.addWithExpire(key, value, c.expiry)
+}
+
+// addWithExpire 加入缓存，并为该条记录单独指定过期时间。ttl为0表示调用方未指定，
+// 使用该缓存自身配置的默认过期时间（c.expiry），而不是让记录立即过期。
+// 若触发了容量淘汰，返回被淘汰的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lfuCache) addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry {
+	if ttl == 0 {
+		ttl = c.expiry
+	}
+	// 1. 如果没有map，先创建map
+	if c.items == nil {
+		c.items = make(map[Key]*list.Element)
+		c.freqs = list.New()
+	}
+	// 2. 如果已存在，更新value与ttl，并提升频率
+	if ele, ok := c.items[key]; ok {
+		item := ele.Value.(*lfuItem)
+		item.value = value
+		item.ttl = time.Now().Add(ttl)
+		c.incrementFreq(ele)
+		return nil
+	}
+	// 3. 如果长度超过，先淘汰频率最低的entry
+	var evicted []evictedEntry
+	if c.maxEntries != 0 && len(c.items) >= c.maxEntries {
+		evicted = c.removeLeastFrequent()
+	}
+	// 4. 新entry从频率1开始
+	item := &lfuItem{key: key, value: value, ttl: time.Now().Add(ttl)}
+	front := c.freqs.Front()
+	var node *lfuFreqNode
+	if front == nil || front.Value.(*lfuFreqNode).freq != 1 {
+		node = &lfuFreqNode{freq: 1, items: list.New()}
+		front = c.freqs.PushFront(node)
+	} else {
+		node = front.Value.(*lfuFreqNode)
+	}
+	item.freqNode = front
+	c.items[key] = node.items.PushBack(item)
+	return evicted
+}
+
+func (c *lfuCache) size() int {
+	if c.items == nil {
+		return 0
+	}
+	return len(c.items)
+}
+
+// get 获取缓存，命中后增加该entry的访问频率
+func (c *lfuCache) get(key Key) (value interface{}, ok bool) {
+	if c.items == nil {
+		c.stat.addMiss()
+		return
+	}
+	ele, hit := c.items[key]
+	if !hit {
+		c.stat.addMiss()
+		return
+	}
+	item := ele.Value.(*lfuItem)
+	if time.Now().After(item.ttl) {
+		c.removeItem(ele)
+		c.stat.addExpiration()
+		c.onEvict(key, ReasonExpired)
+		c.stat.addMiss()
+		return
+	}
+	c.incrementFreq(ele)
+	c.stat.addHit()
+	return item.value, true
+}
+
+// incrementFreq 将entry从其所在的频率桶移动到freq+1的桶
+func (c *lfuCache) incrementFreq(ele *list.Element) {
+	item := ele.Value.(*lfuItem)
+	oldFreqEle := item.freqNode
+	oldNode := oldFreqEle.Value.(*lfuFreqNode)
+	oldNode.items.Remove(ele)
+
+	nextFreq := oldNode.freq + 1
+	nextFreqEle := oldFreqEle.Next()
+	var nextNode *lfuFreqNode
+	if nextFreqEle == nil || nextFreqEle.Value.(*lfuFreqNode).freq != nextFreq {
+		nextNode = &lfuFreqNode{freq: nextFreq, items: list.New()}
+		nextFreqEle = c.freqs.InsertAfter(nextNode, oldFreqEle)
+	} else {
+		nextNode = nextFreqEle.Value.(*lfuFreqNode)
+	}
+
+	item.freqNode = nextFreqEle
+	c.items[item.key] = nextNode.items.PushBack(item)
+
+	// 旧频率桶如果空了，从freqs链表中移除
+	if oldNode.items.Len() == 0 {
+		c.freqs.Remove(oldFreqEle)
+	}
+}
+
+// removeLeastFrequent 淘汰频率最低桶中最早进入的entry，返回被淘汰的记录，
+// 由调用方在释放锁之后再触发OnEvict回调
+func (c *lfuCache) removeLeastFrequent() []evictedEntry {
+	front := c.freqs.Front()
+	if front == nil {
+		return nil
+	}
+	node := front.Value.(*lfuFreqNode)
+	oldest := node.items.Front()
+	if oldest == nil {
+		return nil
+	}
+	key := oldest.Value.(*lfuItem).key
+	c.removeItem(oldest)
+	c.stat.addEviction()
+	return []evictedEntry{{key: key, reason: ReasonCapacity}}
+}
+
+// removeItem 从频率桶与map中删除entry
+func (c *lfuCache) removeItem(ele *list.Element) {
+	item := ele.Value.(*lfuItem)
+	node := item.freqNode.Value.(*lfuFreqNode)
+	node.items.Remove(ele)
+	delete(c.items, item.key)
+	if node.items.Len() == 0 {
+		c.freqs.Remove(item.freqNode)
+	}
+}
+
+// iterate 遍历所有记录，fn返回false时提前终止
+func (c *lfuCache) iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool) {
+	for _, ele := range c.items {
+		item := ele.Value.(*lfuItem)
+		if !fn(item.key, item.value, item.ttl) {
+			return
+		}
+	}
+}
+
+// remove 删除缓存，命中时返回被删除的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lfuCache) remove(key Key) []evictedEntry {
+	if c.items == nil {
+		return nil
+	}
+	if ele, hit := c.items[key]; hit {
+		c.removeItem(ele)
+		return []evictedEntry{{key: key, reason: ReasonManual}}
+	}
+	return nil
+}
+
+// clear 清理，返回清空前的全部记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lfuCache) clear() []evictedEntry {
+	evicted := make([]evictedEntry, 0, len(c.items))
+	for key := range c.items {
+		evicted = append(evicted, evictedEntry{key: key, reason: ReasonClear})
+	}
+	c.items = nil
+	c.freqs = nil
+	return evicted
+}
@@ -0,0 +1,30 @@
+package cache
+
+import "testing"
+
+// TestLFUEvictionOrder 验证LFU在容量不足时淘汰访问频率最低的记录，而不是最久未访问的记录
+func TestLFUEvictionOrder(t *testing.T) {
+	config := NewCacheConfig(0, 3, nil)
+	c := NewCache(config.LFUCacheMode(), config)
+
+	c.Add(1, "a")
+	c.Add(2, "b")
+	c.Add(3, "c")
+
+	// 提升1、2的访问频率，3始终只有加入时的1次
+	c.Get(1)
+	c.Get(1)
+	c.Get(2)
+
+	c.Add(4, "d")
+
+	if _, ok := c.Get(3); ok {
+		t.Errorf("key 3 should have been evicted as the least frequently used entry")
+	}
+	if v, ok := c.Get(1); !ok || v != "a" {
+		t.Errorf("Get(1) = (%v, %v), want (\"a\", true)", v, ok)
+	}
+	if v, ok := c.Get(4); !ok || v != "d" {
+		t.Errorf("Get(4) = (%v, %v), want (\"d\", true)", v, ok)
+	}
+}
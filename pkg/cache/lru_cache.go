@@ -11,6 +11,9 @@ type lruCache struct {
 	ll         *list.List
 	cache      map[Key]*list.Element
 	expiry     time.Duration
+
+	stat      *cacheStat
+	callbacks ChangeCallbackHandler
 }
 
 func newLRU(maxEntries int, expiry time.Duration) *lruCache {
@@ -22,8 +25,31 @@ func newLRU(maxEntries int, expiry time.Duration) *lruCache {
 	}
 }
 
-// add 加入缓存
-func (c *lruCache) add(key Key, value interface{}) {
+// bind 绑定统计计数器与回调处理器
+func (c *lruCache) bind(stat *cacheStat, callbacks ChangeCallbackHandler) {
+	c.stat = stat
+	c.callbacks = callbacks
+}
+
+// onEvict 记录被移除时通知回调处理器
+func (c *lruCache) onEvict(key Key, reason EvictReason) {
+	if c.callbacks != nil {
+		c.callbacks.OnEvict(key, reason)
+	}
+}
+
+// add 加入缓存，使用默认过期时间
+func (c *lruCache) add(key Key, value interface{}) []evictedEntry {
+	return c.addWithExpire(key, value, c.expiry)
+}
+
+// addWithExpire 加入缓存，并为该条记录单独指定过期时间。ttl为0表示调用方未指定，
+// 使用该缓存自身配置的默认过期时间（c.expiry），而不是让记录立即过期。
+// 若触发了容量淘汰，返回被淘汰的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lruCache) addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry {
+	if ttl == 0 {
+		ttl = c.expiry
+	}
 	// 1. 如果没有map，先创建map
 	if c.cache == nil {
 		c.cache = make(map[Key]*list.Element)
@@ -32,17 +58,18 @@ func (c *lruCache) add(key Key, value interface{}) {
 	// 2. 如果能从map中找到，先放到链表最前面，更新 ttl 与 value
 	if ee, ok := c.cache[key]; ok {
 		c.ll.MoveToFront(ee)
-		ee.Value.(*entry).ttl = time.Now().Add(c.expiry)
+		ee.Value.(*entry).ttl = time.Now().Add(ttl)
 		ee.Value.(*entry).value = value
-		return
+		return nil
 	}
 	// 3. 创建 entry 放入最链表前端，并放入map中
-	ele := c.ll.PushFront(&entry{key, time.Now().Add(c.expiry), value})
+	ele := c.ll.PushFront(&entry{key, time.Now().Add(ttl), value})
 	c.cache[key] = ele
 	// 4. 如果长度超过，必须删除最后一个
 	if c.maxEntries != 0 && c.ll.Len() > c.maxEntries {
-		c.removeOldest()
+		return c.removeOldest()
 	}
+	return nil
 }
 
 func (c *lruCache) size() int {
@@ -56,6 +83,7 @@ func (c *lruCache) size() int {
 func (c *lruCache) get(key Key) (value interface{}, ok bool) {
 
 	if c.cache == nil {
+		c.stat.addMiss()
 		return
 	}
 
@@ -63,36 +91,47 @@ func (c *lruCache) get(key Key) (value interface{}, ok bool) {
 	// 没有过期就放入链表前头，并返回
 	if ele, hit := c.cache[key]; hit {
 		if time.Now().After(ele.Value.(*entry).ttl) {
-			c.remove(key)
+			c.removeElement(ele)
+			c.stat.addExpiration()
+			c.onEvict(key, ReasonExpired)
+			c.stat.addMiss()
 			return
 		}
 		c.ll.MoveToFront(ele)
+		c.stat.addHit()
 		return ele.Value.(*entry).value, true
 	}
+	c.stat.addMiss()
 	return
 }
 
-// remove 删除缓存
-func (c *lruCache) remove(key Key) {
+// remove 删除缓存，命中时返回被删除的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lruCache) remove(key Key) []evictedEntry {
 	if c.cache == nil {
-		return
+		return nil
 	}
 	// 找到就删除
 	if ele, hit := c.cache[key]; hit {
 		c.removeElement(ele)
+		return []evictedEntry{{key: key, reason: ReasonManual}}
 	}
+	return nil
 }
 
-// removeOldest 删除最老的
-func (c *lruCache) removeOldest() {
+// removeOldest 删除最老的，返回被删除的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lruCache) removeOldest() []evictedEntry {
 	if c.cache == nil {
-		return
+		return nil
 	}
 	// 找到最老的ele，并删除
 	ele := c.ll.Back()
-	if ele != nil {
-		c.removeElement(ele)
+	if ele == nil {
+		return nil
 	}
+	key := ele.Value.(*entry).key
+	c.removeElement(ele)
+	c.stat.addEviction()
+	return []evictedEntry{{key: key, reason: ReasonCapacity}}
 }
 
 // removeElement 删除元素
@@ -102,8 +141,26 @@ func (c *lruCache) removeElement(e *list.Element) {
 	delete(c.cache, kv.key)
 }
 
-// clear 清除链表与
-func (c *lruCache) clear() {
+// iterate 遍历所有记录，fn返回false时提前终止
+func (c *lruCache) iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool) {
+	if c.ll == nil {
+		return
+	}
+	for e := c.ll.Front(); e != nil; e = e.Next() {
+		kv := e.Value.(*entry)
+		if !fn(kv.key, kv.value, kv.ttl) {
+			return
+		}
+	}
+}
+
+// clear 清除链表与map，返回清空前的全部记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *lruCache) clear() []evictedEntry {
+	evicted := make([]evictedEntry, 0, len(c.cache))
+	for _, ele := range c.cache {
+		evicted = append(evicted, evictedEntry{key: ele.Value.(*entry).key, reason: ReasonClear})
+	}
 	c.ll = nil
 	c.cache = nil
+	return evicted
 }
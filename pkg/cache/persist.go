@@ -0,0 +1,136 @@
+package cache
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"io"
+	"time"
+)
+
+func init() {
+	// 注册常见的内置类型，使未提供SerializeFunc时gob也能编码/解码interface{}
+	gob.Register("")
+	gob.Register(0)
+	gob.Register(int64(0))
+	gob.Register(float64(0))
+	gob.Register(false)
+	gob.Register([]byte(nil))
+}
+
+// serialize 将v编码为[]byte。配置了SerializeFunc时优先使用，否则回退到encoding/gob
+func (cc *CacheConfig) serialize(v interface{}) ([]byte, error) {
+	if cc.SerializeFunc != nil {
+		return cc.SerializeFunc(v)
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&v); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// deserialize 将[]byte还原为value。配置了DeserializeFunc时优先使用，否则回退到encoding/gob
+func (cc *CacheConfig) deserialize(data []byte) (interface{}, error) {
+	if cc.DeserializeFunc != nil {
+		return cc.DeserializeFunc(data)
+	}
+	var v interface{}
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// writeRecord 以长度前缀的二进制格式写入一条记录：keyLen|key|valueLen|value|ttlNanos
+func writeRecord(w io.Writer, keyBytes, valueBytes []byte, ttl time.Duration) error {
+	if err := binary.Write(w, binary.BigEndian, int32(len(keyBytes))); err != nil {
+		return err
+	}
+	if _, err := w.Write(keyBytes); err != nil {
+		return err
+	}
+	if err := binary.Write(w, binary.BigEndian, int32(len(valueBytes))); err != nil {
+		return err
+	}
+	if _, err := w.Write(valueBytes); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.BigEndian, int64(ttl))
+}
+
+// readRecord 读取一条记录，流结束时返回io.EOF
+func readRecord(r io.Reader) (keyBytes, valueBytes []byte, ttl time.Duration, err error) {
+	var keyLen int32
+	if err = binary.Read(r, binary.BigEndian, &keyLen); err != nil {
+		return
+	}
+	keyBytes = make([]byte, keyLen)
+	if _, err = io.ReadFull(r, keyBytes); err != nil {
+		return
+	}
+	var valueLen int32
+	if err = binary.Read(r, binary.BigEndian, &valueLen); err != nil {
+		return
+	}
+	valueBytes = make([]byte, valueLen)
+	if _, err = io.ReadFull(r, valueBytes); err != nil {
+		return
+	}
+	var ttlNanos int64
+	if err = binary.Read(r, binary.BigEndian, &ttlNanos); err != nil {
+		return
+	}
+	ttl = time.Duration(ttlNanos)
+	return
+}
+
+// Snapshot 将所有存活的记录以长度前缀的二进制格式写入w，用于热重启或跨进程持久化。
+// 每条记录依次为key、value（均经CacheConfig.SerializeFunc或encoding/gob编码）及其剩余的过期时间
+func (c *Cache) Snapshot(w io.Writer) error {
+	var err error
+	c.withCacheLock(func() {
+		now := time.Now()
+		c.Cache.iterate(func(key Key, value interface{}, expiresAt time.Time) bool {
+			var keyBytes, valueBytes []byte
+			if keyBytes, err = c.Config.serialize(key); err != nil {
+				return false
+			}
+			if valueBytes, err = c.Config.serialize(value); err != nil {
+				return false
+			}
+			ttl := expiresAt.Sub(now)
+			if ttl <= 0 {
+				// 已过期但尚未被清理的记录不写入快照
+				return true
+			}
+			if err = writeRecord(w, keyBytes, valueBytes, ttl); err != nil {
+				return false
+			}
+			return true
+		})
+	})
+	return err
+}
+
+// Restore 从r中读取Snapshot写入的记录并逐条写回缓存，各记录的剩余过期时间会被保留
+func (c *Cache) Restore(r io.Reader) error {
+	for {
+		keyBytes, valueBytes, ttl, err := readRecord(r)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		key, err := c.Config.deserialize(keyBytes)
+		if err != nil {
+			return err
+		}
+		value, err := c.Config.deserialize(valueBytes)
+		if err != nil {
+			return err
+		}
+		c.SetWithExpire(key, value, ttl)
+	}
+}
@@ -0,0 +1,36 @@
+package cache
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+// TestSnapshotRestoreRoundTrip 验证Snapshot写出的记录能被Restore完整还原到另一个Cache中
+func TestSnapshotRestoreRoundTrip(t *testing.T) {
+	config := NewCacheConfig(0, 10, nil)
+	src := NewCache(config.LRUCacheMode(), config)
+
+	src.Add(1, "a")
+	src.Add(2, "b")
+	src.SetWithExpire(3, "c", time.Hour)
+
+	var buf bytes.Buffer
+	if err := src.Snapshot(&buf); err != nil {
+		t.Fatalf("Snapshot() error: %v", err)
+	}
+
+	dst := NewCache(config.LRUCacheMode(), config)
+	if err := dst.Restore(&buf); err != nil {
+		t.Fatalf("Restore() error: %v", err)
+	}
+
+	if size := dst.Size(); size != 3 {
+		t.Fatalf("Size() = %d after Restore, want 3", size)
+	}
+	for key, want := range map[int]string{1: "a", 2: "b", 3: "c"} {
+		if v, ok := dst.Get(key); !ok || v != want {
+			t.Errorf("Get(%d) = (%v, %v), want (%q, true)", key, v, ok, want)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package cache
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultShardCount ShardedCache默认的分片数量
+const defaultShardCount = 256
+
+// shard 分片，拥有独立的锁与底层ICache实现，互不阻塞
+type shard struct {
+	mu    sync.Mutex
+	cache ICache
+}
+
+// ShardedCache 将缓存拆分为多个独立加锁的分片，缓解高并发下单一锁带来的竞争。
+// 每个key按其哈希值路由到固定的分片，分片数量为2的幂，方便用位运算取模
+type ShardedCache struct {
+	shards []*shard
+	mask   uint32
+}
+
+// newShardedCache 创建一个拥有n个分片的ShardedCache，n会被取整到最近的2的幂，n<=0时使用默认值256。
+// newShard用于创建每个分片底层的ICache实现，每个分片都是完全独立的一份实例
+func newShardedCache(n int, newShard func() ICache) *ShardedCache {
+	if n <= 0 {
+		n = defaultShardCount
+	}
+	n = nextPowerOfTwo(n)
+	shards := make([]*shard, n)
+	for i := range shards {
+		shards[i] = &shard{cache: newShard()}
+	}
+	return &ShardedCache{shards: shards, mask: uint32(n - 1)}
+}
+
+// nextPowerOfTwo 返回大于等于n的最小2的幂
+func nextPowerOfTwo(n int) int {
+	p := 1
+	for p < n {
+		p <<= 1
+	}
+	return p
+}
+
+// hashKey 计算key的fnv32哈希值，用于选择分片。目前兼容string、int，其余类型回退到fmt.Sprintf
+func hashKey(key Key) uint32 {
+	var s string
+	switch k := key.(type) {
+	case string:
+		s = k
+	case int:
+		s = strconv.Itoa(k)
+	default:
+		s = fmt.Sprintf("%v", k)
+	}
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(s))
+	return h.Sum32()
+}
+
+// shardFor 根据key选出对应的分片
+func (s *ShardedCache) shardFor(key Key) *shard {
+	return s.shards[hashKey(key)&s.mask]
+}
+
+// bind 将统计计数器与回调处理器下发给每一个分片
+func (s *ShardedCache) bind(stat *cacheStat, callbacks ChangeCallbackHandler) {
+	for _, sh := range s.shards {
+		sh.cache.bind(stat, callbacks)
+	}
+}
+
+// nextExpireAt 汇总所有支持主动过期的分片，返回其中最早的到期时间
+func (s *ShardedCache) nextExpireAt() (at time.Time, ok bool) {
+	for _, sh := range s.shards {
+		exp, supported := sh.cache.(expirable)
+		if !supported {
+			continue
+		}
+		sh.mu.Lock()
+		shardAt, shardOk := exp.nextExpireAt()
+		sh.mu.Unlock()
+		if shardOk && (!ok || shardAt.Before(at)) {
+			at, ok = shardAt, true
+		}
+	}
+	return
+}
+
+// expireOverdue 让每个支持主动过期的分片各自清理已过期的记录，汇总返回所有被清理的key
+func (s *ShardedCache) expireOverdue(now time.Time) []Key {
+	var expired []Key
+	for _, sh := range s.shards {
+		exp, supported := sh.cache.(expirable)
+		if !supported {
+			continue
+		}
+		sh.mu.Lock()
+		keys := exp.expireOverdue(now)
+		sh.mu.Unlock()
+		expired = append(expired, keys...)
+	}
+	return expired
+}
+
+// iterate 依次遍历每个分片的记录，fn返回false时停止遍历后续分片。
+// 遍历期间只持有当前分片的锁，不会阻塞其他分片
+func (s *ShardedCache) iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool) {
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		stopped := false
+		sh.cache.iterate(func(key Key, value interface{}, expiresAt time.Time) bool {
+			if !fn(key, value, expiresAt) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+		sh.mu.Unlock()
+		if stopped {
+			return
+		}
+	}
+}
+
+func (s *ShardedCache) add(key Key, value interface{}) []evictedEntry {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.cache.add(key, value)
+}
+
+func (s *ShardedCache) addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.cache.addWithExpire(key, value, ttl)
+}
+
+func (s *ShardedCache) size() int {
+	total := 0
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		total += sh.cache.size()
+		sh.mu.Unlock()
+	}
+	return total
+}
+
+func (s *ShardedCache) get(key Key) (value interface{}, ok bool) {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.cache.get(key)
+}
+
+func (s *ShardedCache) remove(key Key) []evictedEntry {
+	sh := s.shardFor(key)
+	sh.mu.Lock()
+	defer sh.mu.Unlock()
+	return sh.cache.remove(key)
+}
+
+func (s *ShardedCache) clear() []evictedEntry {
+	var evicted []evictedEntry
+	for _, sh := range s.shards {
+		sh.mu.Lock()
+		evicted = append(evicted, sh.cache.clear()...)
+		sh.mu.Unlock()
+	}
+	return evicted
+}
@@ -0,0 +1,103 @@
+package cache
+
+import "sync/atomic"
+
+// EvictReason 描述一条记录被移除的原因，随ChangeCallbackHandler.OnEvict回调给使用方，
+// 方便接入自己的可观测性系统
+type EvictReason int
+
+const (
+	// ReasonCapacity 因超出MaxEntries被淘汰
+	ReasonCapacity EvictReason = iota
+	// ReasonExpired 因TTL过期被移除
+	ReasonExpired
+	// ReasonManual 被Cache.Remove手动删除
+	ReasonManual
+	// ReasonClear 被Cache.Clear整体清空
+	ReasonClear
+)
+
+// evictedEntry 记录一次因容量、手动删除或清空而发生的淘汰事件。ICache实现在持有锁的
+// add/addWithExpire/remove/clear路径中只收集这些记录并返回，不直接调用OnEvict回调，
+// 由Cache在释放锁之后统一触发，避免回调函数重新进入Cache时死锁（janitor的sweepExpired
+// 同样在锁外触发过期回调；get路径上发现的过期记录则在持锁期间直接调用OnEvict）
+type evictedEntry struct {
+	key    Key
+	reason EvictReason
+}
+
+// cacheStat 内部维护的原子计数器，由各个ICache实现在命中/未命中/淘汰等事件发生时自增，
+// Cache.Stats()据此生成一份快照
+type cacheStat struct {
+	hits        int64
+	misses      int64
+	lookups     int64
+	evictions   int64
+	expirations int64
+	loaderErrs  int64
+}
+
+func (s *cacheStat) addHit() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.lookups, 1)
+	atomic.AddInt64(&s.hits, 1)
+}
+
+func (s *cacheStat) addMiss() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.lookups, 1)
+	atomic.AddInt64(&s.misses, 1)
+}
+
+func (s *cacheStat) addEviction() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.evictions, 1)
+}
+
+func (s *cacheStat) addExpiration() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.expirations, 1)
+}
+
+func (s *cacheStat) addLoaderErr() {
+	if s == nil {
+		return
+	}
+	atomic.AddInt64(&s.loaderErrs, 1)
+}
+
+// CacheStats 是cacheStat某一时刻的快照
+type CacheStats struct {
+	Hits        int64
+	Misses      int64
+	Lookups     int64
+	Evictions   int64
+	Expirations int64
+	LoaderErrs  int64
+	// HitRate 为 Hits/Lookups，Lookups为0时为0
+	HitRate float64
+}
+
+// Stats 返回当前缓存的命中率、淘汰次数等统计快照
+func (c *Cache) Stats() CacheStats {
+	s := CacheStats{
+		Hits:        atomic.LoadInt64(&c.stat.hits),
+		Misses:      atomic.LoadInt64(&c.stat.misses),
+		Lookups:     atomic.LoadInt64(&c.stat.lookups),
+		Evictions:   atomic.LoadInt64(&c.stat.evictions),
+		Expirations: atomic.LoadInt64(&c.stat.expirations),
+		LoaderErrs:  atomic.LoadInt64(&c.stat.loaderErrs),
+	}
+	if s.Lookups > 0 {
+		s.HitRate = float64(s.Hits) / float64(s.Lookups)
+	}
+	return s
+}
@@ -0,0 +1,39 @@
+package cache
+
+import "testing"
+
+// TestStatsCounters 验证Stats()返回的命中/未命中/淘汰计数与HitRate计算正确
+func TestStatsCounters(t *testing.T) {
+	config := NewCacheConfig(0, 2, nil)
+	c := NewCache(config.LRUCacheMode(), config)
+
+	c.Add(1, "a")
+	c.Add(2, "b")
+
+	if _, ok := c.Get(1); !ok {
+		t.Fatalf("Get(1) should hit")
+	}
+	if _, ok := c.Get(3); ok {
+		t.Fatalf("Get(3) should miss")
+	}
+
+	// 容量已满(maxEntries=2)，加入key 3会淘汰最久未访问的key 2
+	c.Add(3, "c")
+
+	stats := c.Stats()
+	if stats.Hits != 1 {
+		t.Errorf("Hits = %d, want 1", stats.Hits)
+	}
+	if stats.Misses != 1 {
+		t.Errorf("Misses = %d, want 1", stats.Misses)
+	}
+	if stats.Lookups != 2 {
+		t.Errorf("Lookups = %d, want 2", stats.Lookups)
+	}
+	if stats.Evictions != 1 {
+		t.Errorf("Evictions = %d, want 1", stats.Evictions)
+	}
+	if stats.HitRate != 0.5 {
+		t.Errorf("HitRate = %v, want 0.5", stats.HitRate)
+	}
+}
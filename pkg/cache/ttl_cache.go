@@ -1,66 +1,137 @@
 package cache
 
 import (
-	"sort"
+	"container/heap"
 	"time"
 )
 
-// ttlCache 实现ttl过期时间的缓存，使用
+// expItem 记录一个key的过期时间，同时维护自己在expHeap中的下标，方便O(log N)更新/删除
+type expItem struct {
+	key      Key
+	expireAt time.Time
+	index    int
+}
+
+// expHeap 按expireAt从小到大排列的最小堆，堆顶始终是最早过期的记录
+type expHeap []*expItem
+
+func (h expHeap) Len() int { return len(h) }
+
+func (h expHeap) Less(i, j int) bool { return h[i].expireAt.Before(h[j].expireAt) }
+
+func (h expHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *expHeap) Push(x interface{}) {
+	item := x.(*expItem)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *expHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// ttlCache 实现ttl过期时间的缓存，使用最小堆(expHeap)维护过期索引，
+// purgeToCapacity与janitor都可以O(log N)拿到最早过期的记录，而不必每次全量排序
 type ttlCache struct {
 	maxEntries     int
 	updateAgeOnGet bool
 	cache          map[Key]*entry
-	// 使用expiration维护
-	expiration map[int64]Key
-	expiry     time.Duration
+	heap           *expHeap
+	heapIdx        map[Key]*expItem
+	expiry         time.Duration
+
+	stat      *cacheStat
+	callbacks ChangeCallbackHandler
 }
 
 func newTTLCache(maxEntries int, expiry time.Duration, updateAgeOnGet bool) *ttlCache {
+	h := &expHeap{}
+	heap.Init(h)
 	return &ttlCache{
 		maxEntries:     maxEntries,
 		updateAgeOnGet: updateAgeOnGet,
 		cache:          make(map[Key]*entry),
-		expiration:     make(map[int64]Key),
+		heap:           h,
+		heapIdx:        make(map[Key]*expItem),
 		expiry:         expiry,
 	}
 }
 
-func (c *ttlCache) add(key Key, value interface{}) {
+// bind 绑定统计计数器与回调处理器
+func (c *ttlCache) bind(stat *cacheStat, callbacks ChangeCallbackHandler) {
+	c.stat = stat
+	c.callbacks = callbacks
+}
+
+// onEvict 记录被移除时通知回调处理器
+func (c *ttlCache) onEvict(key Key, reason EvictReason) {
+	if c.callbacks != nil {
+		c.callbacks.OnEvict(key, reason)
+	}
+}
+
+// add 加入缓存，使用默认过期时间
+func (c *ttlCache) add(key Key, value interface{}) []evictedEntry {
+	return c.addWithExpire(key, value, c.expiry)
+}
+
+// addWithExpire 加入缓存，并为该条记录单独指定过期时间。ttl为0表示调用方未指定，
+// 使用该缓存自身配置的默认过期时间（c.expiry），而不是让记录立即过期。
+// 若触发了淘汰，返回被淘汰的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *ttlCache) addWithExpire(key Key, value interface{}, ttl time.Duration) []evictedEntry {
+	if ttl == 0 {
+		ttl = c.expiry
+	}
 	// 1. 如果没有就创建map
 	if c.cache == nil {
 		c.cache = make(map[Key]*entry)
-		c.expiration = make(map[int64]Key)
+		c.heap = &expHeap{}
+		heap.Init(c.heap)
+		c.heapIdx = make(map[Key]*expItem)
 	}
 	// 2. 如果本来就有，就放入map中，并更新ttl
 	if ee, ok := c.cache[key]; ok {
 		ee.value = value
-		c.changeTTL(key)
-		return
+		c.changeTTL(key, ttl)
+		return nil
 	}
 
-	// 3. 创建 entry，并放入map中
-	ele := &entry{
-		ttl:   time.Now().Add(c.expiry),
-		value: value,
-		key:   key,
-	}
+	// 3. 创建 entry，并放入map与堆中
+	expireAt := time.Now().Add(ttl)
+	ele := &entry{ttl: expireAt, value: value, key: key}
 	c.cache[key] = ele
-	exp := ele.ttl.UnixNano()
-	c.expiration[exp] = key
+	item := &expItem{key: key, expireAt: expireAt}
+	heap.Push(c.heap, item)
+	c.heapIdx[key] = item
 
 	// 4. 如果长度超过，必须删除最后一个
 	if c.maxEntries != 0 && len(c.cache) > c.maxEntries {
-		c.purgeToCapacity()
+		return c.purgeToCapacity()
 	}
+	return nil
 }
 
-// changeTTL 更新ttl
-func (c *ttlCache) changeTTL(key Key) {
-	if ee, ok := c.cache[key]; ok {
-		delete(c.expiration, ee.ttl.UnixNano())
-		ee.ttl = time.Now().Add(c.expiry)
-		exp := ee.ttl.UnixNano()
-		c.expiration[exp] = key
+// changeTTL 更新ttl，并修复该记录在堆中的位置
+func (c *ttlCache) changeTTL(key Key, ttl time.Duration) {
+	ee, ok := c.cache[key]
+	if !ok {
+		return
+	}
+	ee.ttl = time.Now().Add(ttl)
+	if item, ok := c.heapIdx[key]; ok {
+		item.expireAt = ee.ttl
+		heap.Fix(c.heap, item.index)
 	}
 }
 
@@ -74,57 +145,116 @@ func (c *ttlCache) size() int {
 // get 获取缓存
 func (c *ttlCache) get(key Key) (value interface{}, ok bool) {
 	if c.cache == nil {
+		c.stat.addMiss()
 		return
 	}
 	// 如果获取到，先查看是否过期，如果过期直接返回，
 	// 如果需要更新ttl，则更新
 	if ele, hit := c.cache[key]; hit {
 		if time.Now().After(ele.ttl) {
-			c.remove(key)
+			c.removeElement(ele)
+			c.stat.addExpiration()
+			c.onEvict(key, ReasonExpired)
+			c.stat.addMiss()
 			return
 		}
 		if c.updateAgeOnGet {
-			c.changeTTL(key)
+			c.changeTTL(key, c.expiry)
 		}
+		c.stat.addHit()
 		return ele.value, hit
 	}
+	c.stat.addMiss()
 	return
 }
 
-// remove 删除缓存
-func (c *ttlCache) remove(key Key) {
+// remove 删除缓存，命中时返回被删除的记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *ttlCache) remove(key Key) []evictedEntry {
 	if c.cache == nil {
-		return
+		return nil
 	}
 	if ele, hit := c.cache[key]; hit {
 		c.removeElement(ele)
+		return []evictedEntry{{key: key, reason: ReasonManual}}
 	}
+	return nil
 }
 
-func (c *ttlCache) purgeToCapacity() {
-	expKeys := make([]int64, 0, len(c.expiration))
-	for k := range c.expiration {
-		expKeys = append(expKeys, k)
-	}
-	// 存小到大排序
-	sort.Slice(expKeys, func(i, j int) bool { return expKeys[i] < expKeys[j] })
-	for _, k := range expKeys {
-		if len(c.cache) <= c.maxEntries && k > time.Now().UnixNano() {
-			return
+// purgeToCapacity 淘汰已过期的记录，若仍超出容量则继续淘汰最早过期的记录，并返回所有被
+// 淘汰的记录，由调用方在释放锁之后再触发OnEvict回调。
+// 借助最小堆每次只需O(log N)就能取出最早过期的记录，避免每次都对整个过期索引排序
+func (c *ttlCache) purgeToCapacity() []evictedEntry {
+	var evicted []evictedEntry
+	now := time.Now()
+	for c.heap.Len() > 0 {
+		top := (*c.heap)[0]
+		if len(c.cache) <= c.maxEntries && top.expireAt.After(now) {
+			return evicted
+		}
+		key := top.key
+		expired := !top.expireAt.After(now)
+		c.removeElement(c.cache[key])
+		if expired {
+			c.stat.addExpiration()
+			evicted = append(evicted, evictedEntry{key: key, reason: ReasonExpired})
 		} else {
-			c.remove(c.expiration[k])
+			c.stat.addEviction()
+			evicted = append(evicted, evictedEntry{key: key, reason: ReasonCapacity})
 		}
 	}
+	return evicted
 }
 
-// removeElement 删除元素
+// removeElement 删除元素，同时从堆中摘除对应的过期索引
 func (c *ttlCache) removeElement(e *entry) {
-	delete(c.expiration, e.ttl.UnixNano())
+	if item, ok := c.heapIdx[e.key]; ok {
+		heap.Remove(c.heap, item.index)
+		delete(c.heapIdx, e.key)
+	}
 	delete(c.cache, e.key)
 }
 
-// clear 清理
-func (c *ttlCache) clear() {
+// clear 清理，返回清空前的全部记录，由调用方在释放锁之后再触发OnEvict回调
+func (c *ttlCache) clear() []evictedEntry {
+	evicted := make([]evictedEntry, 0, len(c.cache))
+	for key := range c.cache {
+		evicted = append(evicted, evictedEntry{key: key, reason: ReasonClear})
+	}
 	c.cache = nil
-	c.expiration = nil
+	c.heap = nil
+	c.heapIdx = nil
+	return evicted
+}
+
+// iterate 遍历所有记录，fn返回false时提前终止
+func (c *ttlCache) iterate(fn func(key Key, value interface{}, expiresAt time.Time) bool) {
+	for _, e := range c.cache {
+		if !fn(e.key, e.value, e.ttl) {
+			return
+		}
+	}
+}
+
+// nextExpireAt 返回最早到期的时间，没有记录时ok为false，供janitor决定下一次唤醒时机
+func (c *ttlCache) nextExpireAt() (at time.Time, ok bool) {
+	if c.heap == nil || c.heap.Len() == 0 {
+		return
+	}
+	return (*c.heap)[0].expireAt, true
+}
+
+// expireOverdue 清理所有已经过期的记录并返回被清理的key。
+// 不在此处触发OnEvict回调，调用方（janitor）需要在释放锁之后再通知回调，避免长时间持锁
+func (c *ttlCache) expireOverdue(now time.Time) []Key {
+	var expired []Key
+	for c.heap != nil && c.heap.Len() > 0 {
+		top := (*c.heap)[0]
+		if top.expireAt.After(now) {
+			break
+		}
+		c.removeElement(c.cache[top.key])
+		c.stat.addExpiration()
+		expired = append(expired, top.key)
+	}
+	return expired
 }
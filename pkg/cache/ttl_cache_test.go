@@ -0,0 +1,55 @@
+package cache
+
+import (
+	"testing"
+	"time"
+)
+
+// TestJanitorSweepsExpiredEntriesInBackground 验证后台janitor会依据最小堆主动清理已过期的
+// 记录，而不必等待下一次Get才被动发现——Size()应该在没有任何Get调用的情况下自行下降
+func TestJanitorSweepsExpiredEntriesInBackground(t *testing.T) {
+	config := NewCacheConfig(30*time.Millisecond, 0, nil)
+	c := NewCache(config.TTLCacheMode(false), config)
+	defer c.Close()
+
+	c.Add(1, "a")
+	if size := c.Size(); size != 1 {
+		t.Fatalf("Size() = %d, want 1", size)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for c.Size() != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := c.Size(); size != 0 {
+		t.Errorf("Size() = %d after TTL elapsed, want 0 (janitor should have swept the expired entry)", size)
+	}
+}
+
+// TestJanitorWakesEarlyOnNewInsert 验证新增一条更早过期的记录会让janitor重新计算唤醒时间，
+// 而不是一直睡到此前算好的（此时已经过时的）唤醒时间
+func TestJanitorWakesEarlyOnNewInsert(t *testing.T) {
+	config := NewCacheConfig(time.Hour, 0, nil)
+	c := NewCache(config.TTLCacheMode(false), config)
+	defer c.Close()
+
+	// 先加入一条很久之后才过期的记录，janitor会把下一次唤醒时间定在一小时之后
+	c.Add(1, "a")
+
+	// 再加入一条很快过期的记录，janitor应该被notifyJanitor唤醒并重新计算，
+	// 而不是睡到key 1的一小时过期时间才醒来
+	c.SetWithExpire(2, "b", 30*time.Millisecond)
+
+	deadline := time.Now().Add(time.Second)
+	for c.Size() != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if size := c.Size(); size != 1 {
+		t.Fatalf("Size() = %d after key 2's TTL elapsed, want 1 (janitor should have woken early and swept it)", size)
+	}
+	if v, ok := c.Get(1); !ok || v != "a" {
+		t.Errorf("Get(1) = (%v, %v), want (\"a\", true)", v, ok)
+	}
+}